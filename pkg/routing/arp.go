@@ -0,0 +1,254 @@
+package routing
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// arpCacheTTL controls how long a resolved gateway MAC is reused before
+// we re-ARP for it.
+const arpCacheTTL = 5 * time.Minute
+
+const arpReplyTimeout = 3 * time.Second
+
+type arpCacheEntry struct {
+	mac     net.HardwareAddr
+	expires time.Time
+}
+
+// ARPResolver resolves a neighbor's IP address to its link-layer address
+// over a given interface - via ARP for an IPv4 address, or an IPv6 Neighbor
+// Discovery solicitation for a v6 one - caching results for arpCacheTTL so
+// repeated lookups for the same neighbor don't re-resolve on every packet.
+type ARPResolver struct {
+	mu    sync.Mutex
+	cache map[string]arpCacheEntry
+}
+
+// NewARPResolver creates an empty resolver.
+func NewARPResolver() *ARPResolver {
+	return &ARPResolver{cache: make(map[string]arpCacheEntry)}
+}
+
+// Resolve returns the MAC address of ip reachable over iface, resolving it
+// over the network and reading the reply from handle when the cache has no
+// unexpired entry. IPv4 addresses are resolved with ARP; IPv6 addresses
+// with an NDP neighbor solicitation, since v6 has no ARP equivalent.
+func (r *ARPResolver) Resolve(handle *pcap.Handle, iface *net.Interface, ip net.IP) (net.HardwareAddr, error) {
+	r.mu.Lock()
+	if entry, ok := r.cache[ip.String()]; ok && time.Now().Before(entry.expires) {
+		r.mu.Unlock()
+		return entry.mac, nil
+	}
+	r.mu.Unlock()
+
+	resolve := arpRequest
+	if ip.To4() == nil {
+		resolve = ndpRequest
+	}
+	mac, err := resolve(handle, iface, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cache[ip.String()] = arpCacheEntry{mac: mac, expires: time.Now().Add(arpCacheTTL)}
+	r.mu.Unlock()
+
+	return mac, nil
+}
+
+// arpRequest sends a single ARP request for targetIP over iface and blocks
+// until a matching reply arrives on handle or arpReplyTimeout elapses.
+func arpRequest(handle *pcap.Handle, iface *net.Interface, targetIP net.IP) (net.HardwareAddr, error) {
+	srcIP, err := firstIPv4(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handle.SetBPFFilter(fmt.Sprintf("arp and ether dst %s", iface.HardwareAddr)); err != nil {
+		return nil, fmt.Errorf("routing: setting ARP filter: %w", err)
+	}
+
+	eth := layers.Ethernet{
+		SrcMAC:       iface.HardwareAddr,
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		EthernetType: layers.EthernetTypeARP,
+	}
+	arp := layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   iface.HardwareAddr,
+		SourceProtAddress: srcIP.To4(),
+		DstHwAddress:      net.HardwareAddr{0, 0, 0, 0, 0, 0},
+		DstProtAddress:    targetIP.To4(),
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, &eth, &arp); err != nil {
+		return nil, fmt.Errorf("routing: serializing ARP request: %w", err)
+	}
+	if err := handle.WritePacketData(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("routing: sending ARP request: %w", err)
+	}
+
+	deadline := time.Now().Add(arpReplyTimeout)
+	var replyEth layers.Ethernet
+	var replyARP layers.ARP
+	parser := gopacket.NewDecodingLayerParser(layers.LayerTypeEthernet, &replyEth, &replyARP)
+	decoded := []gopacket.LayerType{}
+
+	for time.Now().Before(deadline) {
+		data, _, err := handle.ReadPacketData()
+		if err != nil {
+			continue
+		}
+		if err := parser.DecodeLayers(data, &decoded); err != nil {
+			continue
+		}
+		for _, layerType := range decoded {
+			if layerType != layers.LayerTypeARP {
+				continue
+			}
+			if replyARP.Operation == layers.ARPReply && net.IP(replyARP.SourceProtAddress).Equal(targetIP) {
+				return net.HardwareAddr(replyARP.SourceHwAddress), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("routing: %w: no ARP reply from %s", ErrNoRoute, targetIP)
+}
+
+// ndpRequest sends a single IPv6 Neighbor Solicitation for targetIP to its
+// solicited-node multicast address, and blocks until a matching Neighbor
+// Advertisement arrives on handle or arpReplyTimeout elapses. This is the
+// v6 equivalent of arpRequest - v6 routers and hosts don't speak ARP.
+func ndpRequest(handle *pcap.Handle, iface *net.Interface, targetIP net.IP) (net.HardwareAddr, error) {
+	srcIP, err := firstIPv6LinkLocal(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handle.SetBPFFilter("icmp6 and ip6[40] == 136"); err != nil {
+		return nil, fmt.Errorf("routing: setting NDP filter: %w", err)
+	}
+
+	eth := layers.Ethernet{
+		SrcMAC:       iface.HardwareAddr,
+		DstMAC:       solicitedNodeMAC(targetIP),
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	ip6 := layers.IPv6{
+		Version:    6,
+		HopLimit:   255,
+		NextHeader: layers.IPProtocolICMPv6,
+		SrcIP:      srcIP,
+		DstIP:      solicitedNodeMulticast(targetIP),
+	}
+	icmp6 := layers.ICMPv6{
+		TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeNeighborSolicitation, 0),
+	}
+	icmp6.SetNetworkLayerForChecksum(&ip6)
+	ns := layers.ICMPv6NeighborSolicitation{
+		TargetAddress: targetIP,
+		Options: layers.ICMPv6Options{
+			{Type: layers.ICMPv6OptSourceAddress, Data: iface.HardwareAddr},
+		},
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, &eth, &ip6, &icmp6, &ns); err != nil {
+		return nil, fmt.Errorf("routing: serializing NDP request: %w", err)
+	}
+	if err := handle.WritePacketData(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("routing: sending NDP request: %w", err)
+	}
+
+	deadline := time.Now().Add(arpReplyTimeout)
+	var replyEth layers.Ethernet
+	var replyIP6 layers.IPv6
+	var replyICMP6 layers.ICMPv6
+	var replyNA layers.ICMPv6NeighborAdvertisement
+	parser := gopacket.NewDecodingLayerParser(layers.LayerTypeEthernet, &replyEth, &replyIP6, &replyICMP6, &replyNA)
+	decoded := []gopacket.LayerType{}
+
+	for time.Now().Before(deadline) {
+		data, _, err := handle.ReadPacketData()
+		if err != nil {
+			continue
+		}
+		if err := parser.DecodeLayers(data, &decoded); err != nil {
+			continue
+		}
+		for _, layerType := range decoded {
+			if layerType != layers.LayerTypeICMPv6NeighborAdvertisement {
+				continue
+			}
+			if !replyNA.TargetAddress.Equal(targetIP) {
+				continue
+			}
+			for _, opt := range replyNA.Options {
+				if opt.Type == layers.ICMPv6OptTargetAddress {
+					return net.HardwareAddr(opt.Data), nil
+				}
+			}
+			return replyEth.SrcMAC, nil
+		}
+	}
+
+	return nil, fmt.Errorf("routing: %w: no NDP advertisement from %s", ErrNoRoute, targetIP)
+}
+
+// solicitedNodeMulticast derives the IPv6 solicited-node multicast address
+// ff02::1:ffXX:XXXX a neighbor solicitation for target must be sent to, per
+// RFC 4291: the well-known prefix plus the low 24 bits of target.
+func solicitedNodeMulticast(target net.IP) net.IP {
+	t := target.To16()
+	return net.IP{0xff, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0xff, t[13], t[14], t[15]}
+}
+
+// solicitedNodeMAC is the Ethernet multicast address (33:33:ff:XX:XX:XX)
+// corresponding to solicitedNodeMulticast(target).
+func solicitedNodeMAC(target net.IP) net.HardwareAddr {
+	t := target.To16()
+	return net.HardwareAddr{0x33, 0x33, 0xff, t[13], t[14], t[15]}
+}
+
+func firstIPv4(iface *net.Interface) (net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if ok && ipnet.IP.To4() != nil {
+			return ipnet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("routing: no IPv4 address on %s", iface.Name)
+}
+
+func firstIPv6LinkLocal(iface *net.Interface) (net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if ok && ipnet.IP.To4() == nil && ipnet.IP.IsLinkLocalUnicast() {
+			return ipnet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("routing: no IPv6 link-local address on %s", iface.Name)
+}
@@ -0,0 +1,111 @@
+//go:build linux
+// +build linux
+
+package routing
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// newRouteGetRequest builds an RTM_GETROUTE netlink message asking the
+// kernel to resolve the route it would use for dst, equivalent to
+// `ip route get <dst>`.
+func newRouteGetRequest(dst net.IP) ([]byte, error) {
+	is4 := dst.To4() != nil
+	family := uint8(unix.AF_INET)
+	addr := dst.To4()
+	if !is4 {
+		family = unix.AF_INET6
+		addr = dst.To16()
+	}
+	if addr == nil {
+		return nil, fmt.Errorf("routing: invalid destination address %v", dst)
+	}
+
+	rtmsg := unix.RtMsg{
+		Family:   family,
+		Dst_len:  uint8(len(addr) * 8),
+		Table:    unix.RT_TABLE_MAIN,
+		Protocol: unix.RTPROT_UNSPEC,
+		Scope:    unix.RT_SCOPE_UNIVERSE,
+		Type:     unix.RTN_UNSPEC,
+		Flags:    0,
+	}
+
+	dstAttr := netlinkAttr(unix.RTA_DST, addr)
+
+	rtmsgBytes := (*[unix.SizeofRtMsg]byte)(unsafe.Pointer(&rtmsg))[:]
+	payload := append(append([]byte{}, rtmsgBytes...), dstAttr...)
+
+	hdr := unix.NlMsghdr{
+		Len:   uint32(unix.SizeofNlMsghdr + len(payload)),
+		Type:  unix.RTM_GETROUTE,
+		Flags: unix.NLM_F_REQUEST,
+		Seq:   1,
+	}
+	hdrBytes := (*[unix.SizeofNlMsghdr]byte)(unsafe.Pointer(&hdr))[:]
+
+	return append(hdrBytes, payload...), nil
+}
+
+// netlinkAttr encodes a single netlink attribute (type + length-prefixed,
+// 4-byte aligned value).
+func netlinkAttr(attrType uint16, data []byte) []byte {
+	l := unix.SizeofNlAttr + len(data)
+	buf := make([]byte, align4(l))
+	binary.LittleEndian.PutUint16(buf[0:2], uint16(l))
+	binary.LittleEndian.PutUint16(buf[2:4], attrType)
+	copy(buf[unix.SizeofNlAttr:], data)
+	return buf
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// parseRouteReply walks the RTM_NEWROUTE reply looking for RTA_OIF (outgoing
+// interface index) and RTA_GATEWAY (next-hop, absent when the destination is
+// on-link).
+func parseRouteReply(b []byte) (oif int, gateway net.IP, err error) {
+	for len(b) >= unix.SizeofNlMsghdr {
+		hdr := (*unix.NlMsghdr)(unsafe.Pointer(&b[0]))
+		msgLen := int(hdr.Len)
+		if msgLen < unix.SizeofNlMsghdr || msgLen > len(b) {
+			return 0, nil, fmt.Errorf("routing: %w: malformed netlink message", ErrNoRoute)
+		}
+
+		switch hdr.Type {
+		case unix.NLMSG_ERROR:
+			return 0, nil, fmt.Errorf("routing: %w: kernel returned an error for RTM_GETROUTE", ErrNoRoute)
+		case unix.RTM_NEWROUTE:
+			attrs := b[unix.SizeofNlMsghdr+unix.SizeofRtMsg : msgLen]
+			for len(attrs) >= unix.SizeofNlAttr {
+				attrLen := int(binary.LittleEndian.Uint16(attrs[0:2]))
+				attrType := binary.LittleEndian.Uint16(attrs[2:4])
+				if attrLen < unix.SizeofNlAttr || attrLen > len(attrs) {
+					break
+				}
+				val := attrs[unix.SizeofNlAttr:attrLen]
+				switch attrType {
+				case unix.RTA_OIF:
+					oif = int(binary.LittleEndian.Uint32(val))
+				case unix.RTA_GATEWAY:
+					gateway = append(net.IP{}, val...)
+				}
+				attrs = attrs[align4(attrLen):]
+			}
+		}
+
+		b = b[align4(msgLen):]
+	}
+
+	if oif == 0 {
+		return 0, nil, fmt.Errorf("routing: %w", ErrNoRoute)
+	}
+	return oif, gateway, nil
+}
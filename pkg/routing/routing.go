@@ -0,0 +1,60 @@
+package routing
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrNoRoute is returned when no route to the destination can be determined,
+// either because the kernel routing table has no matching entry or because
+// the gateway for an off-link destination could not be resolved.
+var ErrNoRoute = errors.New("routing: no route to host")
+
+// Route describes how a packet to a given destination should be sent: which
+// interface it leaves from, which local address it should carry as source,
+// and - for destinations outside the directly connected subnet - the
+// next-hop gateway it must be handed to.
+type Route struct {
+	Interface *net.Interface
+	SrcIP     net.IP
+
+	// Gateway is nil when the destination is on a directly connected
+	// subnet and packets can be addressed straight to it.
+	Gateway net.IP
+}
+
+// OnLink reports whether the destination is directly reachable on the local
+// subnet, i.e. no gateway hop is required.
+func (r *Route) OnLink() bool {
+	return r.Gateway == nil
+}
+
+// Router resolves the outgoing interface, source address and next-hop
+// gateway for a destination IP, consulting the kernel routing table.
+// Implementations are platform specific; use New to obtain one for the
+// current OS.
+type Router interface {
+	Route(dst net.IP) (*Route, error)
+}
+
+// New returns a Router backed by the current platform's routing table.
+func New() (Router, error) {
+	return newPlatformRouter()
+}
+
+// dialSourceIP asks the kernel which local address it would use to reach
+// dst, without sending any traffic. It's portable everywhere, so the
+// non-Linux routers all use it to pick a source address once they've
+// otherwise resolved the outgoing interface and gateway.
+func dialSourceIP(dst net.IP) (net.IP, error) {
+	network := "udp4"
+	if dst.To4() == nil {
+		network = "udp6"
+	}
+	conn, err := net.Dial(network, net.JoinHostPort(dst.String(), "12345"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,77 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package routing
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// bsdRouter resolves routes by shelling out to route(8) and parsing
+// `route -n get <dst>`'s output. The BSD family and Darwin expose the
+// kernel routing table through a PF_ROUTE socket whose message layout
+// differs enough between them that parsing route(8)'s own output - which
+// every one of these OSes ships and agrees on the format of - is less
+// fragile than reimplementing that socket protocol per platform.
+type bsdRouter struct{}
+
+func newPlatformRouter() (Router, error) {
+	return &bsdRouter{}, nil
+}
+
+func (r *bsdRouter) Route(dst net.IP) (*Route, error) {
+	ifaceName, gateway, err := routeGet(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("routing: resolving interface %s: %w", ifaceName, err)
+	}
+
+	srcIP, err := dialSourceIP(dst)
+	if err != nil {
+		return nil, fmt.Errorf("routing: %w: %s", ErrNoRoute, err)
+	}
+
+	route := &Route{Interface: iface, SrcIP: srcIP}
+	if gateway != nil && !gateway.Equal(dst) {
+		route.Gateway = gateway
+	}
+	return route, nil
+}
+
+// routeGet runs `route -n get <dst>` and picks the "interface:" and
+// "gateway:" fields out of its output, e.g.:
+//
+//	   route to: 93.184.216.34
+//	destination: default
+//	       mask: default
+//	    gateway: 192.168.1.1
+//	  interface: en0
+func routeGet(dst net.IP) (iface string, gateway net.IP, err error) {
+	out, err := exec.Command("route", "-n", "get", dst.String()).Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("routing: %w: route get %s: %s", ErrNoRoute, dst, err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "interface:"):
+			iface = strings.TrimSpace(strings.TrimPrefix(line, "interface:"))
+		case strings.HasPrefix(line, "gateway:"):
+			gateway = net.ParseIP(strings.TrimSpace(strings.TrimPrefix(line, "gateway:")))
+		}
+	}
+	if iface == "" {
+		return "", nil, fmt.Errorf("routing: %w: no interface in route output for %s", ErrNoRoute, dst)
+	}
+	return iface, gateway, nil
+}
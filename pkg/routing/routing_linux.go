@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+package routing
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// linuxRouter resolves routes by issuing an RTM_GETROUTE netlink request to
+// the kernel, mirroring what `ip route get <dst>` does.
+type linuxRouter struct{}
+
+func newPlatformRouter() (Router, error) {
+	return &linuxRouter{}, nil
+}
+
+func (r *linuxRouter) Route(dst net.IP) (*Route, error) {
+	sock, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("routing: opening netlink socket: %w", err)
+	}
+	defer unix.Close(sock)
+
+	req, err := newRouteGetRequest(dst)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Sendto(sock, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("routing: sending RTM_GETROUTE: %w", err)
+	}
+
+	buf := make([]byte, unix.Getpagesize())
+	n, _, err := unix.Recvfrom(sock, buf, 0)
+	if err != nil {
+		return nil, fmt.Errorf("routing: reading netlink reply: %w", err)
+	}
+
+	oif, gateway, err := parseRouteReply(buf[:n])
+	if err != nil {
+		return nil, err
+	}
+
+	iface, err := net.InterfaceByIndex(oif)
+	if err != nil {
+		return nil, fmt.Errorf("routing: resolving interface index %d: %w", oif, err)
+	}
+
+	srcIP, err := preferredSrcIP(iface, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	route := &Route{Interface: iface, SrcIP: srcIP}
+	if gateway != nil && !gateway.Equal(dst) {
+		route.Gateway = gateway
+	}
+	return route, nil
+}
+
+// preferredSrcIP returns the first address on iface of the same family as
+// dst. It does not ask the kernel which source address it would pick for
+// dst, so on an interface with several such addresses the kernel's choice
+// can differ.
+func preferredSrcIP(iface *net.Interface, dst net.IP) (net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("routing: listing addresses for %s: %w", iface.Name, err)
+	}
+	wantV4 := dst.To4() != nil
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if (ipnet.IP.To4() != nil) == wantV4 {
+			return ipnet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("routing: %w: no usable address on %s", ErrNoRoute, iface.Name)
+}
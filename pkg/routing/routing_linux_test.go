@@ -0,0 +1,43 @@
+//go:build linux
+// +build linux
+
+package routing
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPreferredSrcIPv6(t *testing.T) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatalf("listing interfaces: %v", err)
+	}
+
+	var iface *net.Interface
+	var want net.IP
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if ok && ipnet.IP.To4() == nil && !ipnet.IP.IsLoopback() {
+				iface = &ifaces[i]
+				want = ipnet.IP
+			}
+		}
+	}
+	if iface == nil {
+		t.Skip("no non-loopback IPv6 address available in this environment")
+	}
+
+	got, err := preferredSrcIP(iface, net.ParseIP("2001:db8::1"))
+	if err != nil {
+		t.Fatalf("preferredSrcIP returned error: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Fatalf("preferredSrcIP = %s, want %s", got, want)
+	}
+}
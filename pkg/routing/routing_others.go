@@ -0,0 +1,110 @@
+//go:build !linux && !windows && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!windows,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package routing
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/projectdiscovery/naabu/pkg/log"
+)
+
+// genericRouter is the last-resort fallback for platforms with neither a
+// netlink-style API (routing_linux.go) nor a route(8)/GetIpForwardTable2
+// equivalent we've wired up (routing_bsd.go, routing_windows.go). It infers
+// the outgoing interface and source address via a connected UDP socket, and
+// guesses that any destination outside the interface's directly connected
+// subnets is routed through the ".1" host on that subnet - a guess that's
+// wrong on any network not laid out like a home router's, so every use
+// logs a warning.
+type genericRouter struct{}
+
+func newPlatformRouter() (Router, error) {
+	return &genericRouter{}, nil
+}
+
+func (r *genericRouter) Route(dst net.IP) (*Route, error) {
+	srcIP, err := dialSourceIP(dst)
+	if err != nil {
+		return nil, fmt.Errorf("routing: %w: %s", ErrNoRoute, err)
+	}
+
+	iface, err := interfaceByIP(srcIP)
+	if err != nil {
+		return nil, err
+	}
+
+	route := &Route{Interface: iface, SrcIP: srcIP}
+	if onLink, err := isDirectlyConnected(iface, dst); err == nil && !onLink {
+		gw, err := defaultGateway(iface, dst)
+		if err != nil {
+			return nil, err
+		}
+		route.Gateway = gw
+	}
+	return route, nil
+}
+
+func interfaceByIP(ip net.IP) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if ok && ipnet.IP.Equal(ip) {
+				ifaceCopy := iface
+				return &ifaceCopy, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("routing: %w: no interface owns address %s", ErrNoRoute, ip)
+}
+
+func isDirectlyConnected(iface *net.Interface, dst net.IP) (bool, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false, err
+	}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if ok && ipnet.Contains(dst) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// defaultGateway has no portable way to ask this platform's kernel directly,
+// so it falls back to the conventional .1 host on the interface's primary
+// subnet - a guess that holds for home-router-style /24s but not for most
+// enterprise or cloud layouts, so every call is logged loudly rather than
+// silently trusted.
+func defaultGateway(iface *net.Interface, dst net.IP) (net.IP, error) {
+	log.Warningf("routing: no native route lookup on this platform; guessing the gateway for %s is the .1 host on %s - this may be wrong\n", dst, iface.Name)
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	wantV4 := dst.To4() != nil
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || (ipnet.IP.To4() != nil) != wantV4 {
+			continue
+		}
+		gw := ipnet.IP.Mask(ipnet.Mask)
+		if gw == nil {
+			continue
+		}
+		gw[len(gw)-1]++
+		return gw, nil
+	}
+	return nil, fmt.Errorf("routing: %w: no gateway candidate on %s", ErrNoRoute, iface.Name)
+}
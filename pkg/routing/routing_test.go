@@ -0,0 +1,78 @@
+package routing
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestRouteIPv6Loopback(t *testing.T) {
+	router, err := New()
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	route, err := router.Route(net.ParseIP("::1"))
+	if err != nil {
+		t.Skipf("no IPv6 route available in this environment: %v", err)
+	}
+
+	if route.SrcIP == nil || route.SrcIP.To4() != nil {
+		t.Fatalf("expected an IPv6 source address, got %v", route.SrcIP)
+	}
+	if !route.OnLink() {
+		t.Fatalf("expected ::1 to be on-link, got gateway %v", route.Gateway)
+	}
+}
+
+// TestRouteIPv6LinkLocalScoped exercises a link-local address that, on the
+// wire and in iface.Addrs(), always carries a zone/scope ID - Router.Route
+// itself takes a bare net.IP with no Zone field, so the zone has to be
+// stripped before the call, same as any other caller resolving a user-
+// supplied "fe80::1%eth0"-style address.
+func TestRouteIPv6LinkLocalScoped(t *testing.T) {
+	zoned, err := firstLinkLocalWithZone()
+	if err != nil {
+		t.Skipf("no IPv6 link-local address with a zone available in this environment: %v", err)
+	}
+
+	router, err := New()
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	route, err := router.Route(zoned.IP)
+	if err != nil {
+		t.Skipf("no route to %s in this environment: %v", zoned, err)
+	}
+
+	if route.SrcIP == nil || route.SrcIP.To4() != nil {
+		t.Fatalf("expected an IPv6 source address, got %v", route.SrcIP)
+	}
+	if !route.OnLink() {
+		t.Fatalf("expected link-local %s to be on-link, got gateway %v", zoned, route.Gateway)
+	}
+}
+
+// firstLinkLocalWithZone returns the first IPv6 link-local address found on
+// any interface, carrying its interface name as Zone - the scope ID a real
+// "fe80::...%eth0" address always needs to be unambiguous.
+func firstLinkLocalWithZone() (*net.IPAddr, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if ok && ipnet.IP.To4() == nil && ipnet.IP.IsLinkLocalUnicast() {
+				return &net.IPAddr{IP: ipnet.IP, Zone: iface.Name}, nil
+			}
+		}
+	}
+	return nil, errors.New("no IPv6 link-local address found on any interface")
+}
@@ -0,0 +1,64 @@
+//go:build windows
+// +build windows
+
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// windowsRouter resolves routes via the Find-NetRoute PowerShell cmdlet,
+// which wraps the same IP Forward Table (GetIpForwardTable2) the kernel
+// itself consults - there's no cgo-free way to call that API directly
+// without a much larger syscall binding, and Find-NetRoute ships on every
+// supported Windows release.
+type windowsRouter struct{}
+
+func newPlatformRouter() (Router, error) {
+	return &windowsRouter{}, nil
+}
+
+// netRoute mirrors the two Find-NetRoute fields Route needs.
+type netRoute struct {
+	NextHop        string `json:"NextHop"`
+	InterfaceAlias string `json:"InterfaceAlias"`
+}
+
+func (r *windowsRouter) Route(dst net.IP) (*Route, error) {
+	script := fmt.Sprintf(
+		"Find-NetRoute -RemoteIPAddress '%s' | Select-Object -First 1 NextHop,InterfaceAlias | ConvertTo-Json",
+		dst,
+	)
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).Output()
+	if err != nil {
+		return nil, fmt.Errorf("routing: %w: Find-NetRoute %s: %s", ErrNoRoute, dst, err)
+	}
+
+	var rt netRoute
+	if err := json.Unmarshal(out, &rt); err != nil {
+		return nil, fmt.Errorf("routing: parsing Find-NetRoute output for %s: %w", dst, err)
+	}
+	if rt.InterfaceAlias == "" {
+		return nil, fmt.Errorf("routing: %w: no route returned for %s", ErrNoRoute, dst)
+	}
+
+	iface, err := net.InterfaceByName(rt.InterfaceAlias)
+	if err != nil {
+		return nil, fmt.Errorf("routing: resolving interface %s: %w", rt.InterfaceAlias, err)
+	}
+
+	srcIP, err := dialSourceIP(dst)
+	if err != nil {
+		return nil, fmt.Errorf("routing: %w: %s", ErrNoRoute, err)
+	}
+
+	route := &Route{Interface: iface, SrcIP: srcIP}
+	if gw := net.ParseIP(strings.TrimSpace(rt.NextHop)); gw != nil && !gw.IsUnspecified() && !gw.Equal(dst) {
+		route.Gateway = gw
+	}
+	return route, nil
+}
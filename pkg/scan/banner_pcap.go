@@ -0,0 +1,204 @@
+package scan
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"github.com/google/gopacket/tcpassembly"
+	"github.com/phayes/freeport"
+)
+
+// bannerHandshakeState tracks one port's three-way handshake so the read
+// loop below knows when it's safe to push the probe and start feeding the
+// reply to tcpassembly.
+type bannerHandshakeState int
+
+const (
+	bannerSynSent bannerHandshakeState = iota
+	bannerEstablished
+)
+
+// bannerConn is the per-port state GrabBannersPcap needs to drive a TCP
+// connection by hand - there's no kernel socket backing it, so we have to
+// track the handshake and sequence numbers ourselves.
+type bannerConn struct {
+	port  int
+	state bannerHandshakeState
+	seq   uint32
+	ack   uint32
+}
+
+// bannerStream buffers one reassembled TCP flow's payload for
+// GrabBannersPcap. It satisfies tcpassembly.Stream.
+type bannerStream struct {
+	port int
+	buf  []byte
+}
+
+func (b *bannerStream) Reassembled(reassembly []tcpassembly.Reassembly) {
+	for _, r := range reassembly {
+		if len(b.buf) >= bannerReadLimit {
+			continue
+		}
+		b.buf = append(b.buf, r.Bytes...)
+	}
+}
+
+func (b *bannerStream) ReassemblyComplete() {}
+
+// bannerStreamFactory hands back one bannerStream per server port, keyed
+// by the TCP flow's source port - that's the open port replying to our
+// probe, since every probe here originates from the same local srcPort.
+type bannerStreamFactory struct {
+	mu      sync.Mutex
+	streams map[int]*bannerStream
+}
+
+func newBannerStreamFactory() *bannerStreamFactory {
+	return &bannerStreamFactory{streams: make(map[int]*bannerStream)}
+}
+
+func (f *bannerStreamFactory) New(_, tcpFlow gopacket.Flow) tcpassembly.Stream {
+	port := int(binary.BigEndian.Uint16(tcpFlow.Src().Raw()))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stream, ok := f.streams[port]
+	if !ok {
+		stream = &bannerStream{port: port}
+		f.streams[port] = stream
+	}
+	return stream
+}
+
+// GrabBannersPcap grabs banners from many open ports on s.host without
+// opening a kernel socket per port: it drives the handshake and the HEAD
+// probe for every port over a single raw socket, then hands replies read
+// off handle to a tcpassembly.Assembler that reassembles each flow's bytes
+// in the background. This trades GrabBanners' simplicity for the ability
+// to grab thousands of banners without exhausting file descriptors.
+//
+// Only IPv4 targets are supported, matching buildFragments and
+// sendFragments in fragment.go.
+func (s *Scanner) GrabBannersPcap(handle *pcap.Handle, ports map[int]struct{}) (map[int]Banner, error) {
+	factory := newBannerStreamFactory()
+	pool := tcpassembly.NewStreamPool(factory)
+	assembler := tcpassembly.NewAssembler(pool)
+
+	rawPort, err := freeport.GetFreePort()
+	if err != nil {
+		return nil, err
+	}
+	if err := handle.SetBPFFilter(fmt.Sprintf("tcp and dst port %d and src host %s", rawPort, s.host)); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenPacket("ip4:tcp", "0.0.0.0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conns := make(map[int]*bannerConn, len(ports))
+	var connsMu sync.Mutex
+	for port := range ports {
+		conns[port] = &bannerConn{port: port, seq: uint32(1000000000 + rand.Intn(899999999))}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var (
+			eth    layers.Ethernet
+			ip4    layers.IPv4
+			tcp    layers.TCP
+			parser *gopacket.DecodingLayerParser
+		)
+		if s.networkInterface.HardwareAddr != nil {
+			parser = gopacket.NewDecodingLayerParser(layers.LayerTypeEthernet, &eth, &ip4, &tcp)
+		} else {
+			parser = gopacket.NewDecodingLayerParser(layers.LayerTypeIPv4, &ip4, &tcp)
+		}
+		decoded := []gopacket.LayerType{}
+
+		for {
+			data, ci, err := handle.ReadPacketData()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				continue
+			}
+			if err := parser.DecodeLayers(data, &decoded); err != nil {
+				continue
+			}
+
+			if tcp.DstPort != layers.TCPPort(rawPort) {
+				continue
+			}
+			connsMu.Lock()
+			bc, tracked := conns[int(tcp.SrcPort)]
+			if !tracked {
+				connsMu.Unlock()
+				continue
+			}
+
+			switch {
+			case bc.state == bannerSynSent && tcp.SYN && tcp.ACK:
+				bc.ack = tcp.Seq + 1
+				bc.state = bannerEstablished
+				connsMu.Unlock()
+
+				reply := layers.IPv4{SrcIP: s.srcIP, DstIP: s.host, Version: 4, TTL: 255, Protocol: layers.IPProtocolTCP}
+				ack := layers.TCP{SrcPort: layers.TCPPort(rawPort), DstPort: tcp.SrcPort, ACK: true, PSH: true, Seq: bc.seq, Ack: bc.ack, Window: 1024}
+				ack.SetNetworkLayerForChecksum(&reply)
+				probe := gopacket.Payload([]byte("HEAD / HTTP/1.0\r\n\r\n"))
+				_, _ = s.send(conn, handle, &reply, &ack, probe)
+
+			case bc.state == bannerEstablished && len(tcp.Payload) > 0:
+				connsMu.Unlock()
+				assembler.AssembleWithTimestamp(ip4.NetworkFlow(), &tcp, ci.Timestamp)
+
+			default:
+				connsMu.Unlock()
+			}
+		}
+	}()
+
+	for port := range ports {
+		bc := conns[port]
+		ip4 := layers.IPv4{SrcIP: s.srcIP, DstIP: s.host, Version: 4, TTL: 255, Protocol: layers.IPProtocolTCP}
+		syn := layers.TCP{SrcPort: layers.TCPPort(rawPort), DstPort: layers.TCPPort(port), SYN: true, Seq: bc.seq, Window: 1024}
+		syn.SetNetworkLayerForChecksum(&ip4)
+		if _, err := s.send(conn, handle, &ip4, &syn); err != nil {
+			continue
+		}
+	}
+
+	time.Sleep(s.timeout)
+	handle.Close()
+	<-done
+	assembler.FlushAll()
+
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+	banners := make(map[int]Banner, len(factory.streams))
+	for port, stream := range factory.streams {
+		if len(stream.buf) == 0 {
+			continue
+		}
+		data := stream.buf
+		if len(data) > bannerReadLimit {
+			data = data[:bannerReadLimit]
+		}
+		banners[port] = Banner{Data: data, Service: guessService(port, data)}
+	}
+	return banners, nil
+}
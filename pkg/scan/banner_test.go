@@ -0,0 +1,53 @@
+package scan
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGuessService(t *testing.T) {
+	tests := []struct {
+		name string
+		port int
+		data []byte
+		want string
+	}{
+		{name: "SSH banner", port: 22, data: []byte("SSH-2.0-OpenSSH_9.6\r\n"), want: "ssh"},
+		{name: "HTTP status line", port: 8080, data: []byte("HTTP/1.1 200 OK\r\n"), want: "http"},
+		{name: "SMTP/FTP greeting", port: 21, data: []byte("220 ftp.example.com FTP ready\r\n"), want: "ftp/smtp"},
+		{name: "unrecognized banner falls back to the port", port: 9999, data: []byte("garbage"), want: "unknown(9999)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := guessService(tt.port, tt.data); got != tt.want {
+				t.Fatalf("guessService(%d, %q) = %q, want %q", tt.port, tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrabTLSBanner(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	conn, err := net.DialTimeout("tcp", server.Listener.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("dialing test server: %v", err)
+	}
+	defer conn.Close()
+
+	banner, err := grabTLSBanner(conn, time.Second)
+	if err != nil {
+		t.Fatalf("grabTLSBanner returned error: %v", err)
+	}
+	if banner.Service != "tls" {
+		t.Fatalf("banner.Service = %q, want %q", banner.Service, "tls")
+	}
+	if len(banner.Data) == 0 {
+		t.Fatalf("expected a non-empty certificate identity in banner.Data")
+	}
+}
@@ -0,0 +1,9 @@
+package scan
+
+import "errors"
+
+// ErrHostUnreachable is returned when the destination's route cannot be
+// resolved (no route to the subnet) or its gateway's MAC address cannot be
+// learned via ARP, as distinct from a scan simply timing out with no open
+// ports found.
+var ErrHostUnreachable = errors.New("scan: host unreachable")
@@ -0,0 +1,108 @@
+package scan
+
+import (
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// defaultFragmentMTU keeps the 20-byte IPv4 header plus an 8-byte payload,
+// the same granularity nmap's -f flag uses.
+const defaultFragmentMTU = 28
+
+const ipv4HeaderLen = 20
+
+// buildFragments splits tcp's serialized bytes across IPv4 fragments no
+// larger than (mtu - 20-byte IPv4 header), rounded down to a multiple of 8
+// as required by the 3-bit-shifted IPv4 fragment offset field. The TCP
+// checksum is computed once, over the whole segment, before it's split -
+// recomputing it per fragment isn't meaningful since the TCP header only
+// appears whole once the fragments are reassembled.
+//
+// This only obscures the TCP layer from stateless filters that don't
+// reassemble; any stateful firewall reassembles the fragments before
+// inspecting them.
+func buildFragments(ip4 layers.IPv4, tcp layers.TCP, mtu int) ([][]byte, error) {
+	if mtu <= 0 {
+		mtu = defaultFragmentMTU
+	}
+
+	tcpBuf := gopacket.NewSerializeBuffer()
+	tcpOpts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(tcpBuf, tcpOpts, &tcp); err != nil {
+		return nil, err
+	}
+	payload := tcpBuf.Bytes()
+
+	maxSegmentSize := (mtu - ipv4HeaderLen) &^ 7
+	if maxSegmentSize <= 0 {
+		maxSegmentSize = 8
+	}
+
+	var fragments [][]byte
+	for offset := 0; offset < len(payload); offset += maxSegmentSize {
+		end := offset + maxSegmentSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunk := payload[offset:end]
+
+		frag := ip4
+		// 5 words (20 bytes): scan.go never sets IPv4 options on the
+		// fragmented layer, so there's no variable-length options section
+		// to account for here.
+		frag.IHL = 5
+		frag.Length = uint16(ipv4HeaderLen + len(chunk))
+		frag.FragOffset = uint16(offset >> 3)
+		frag.Flags = 0
+		if end < len(payload) {
+			frag.Flags = layers.IPv4MoreFragments
+		}
+
+		buf := gopacket.NewSerializeBuffer()
+		// FixLengths must stay false: gopacket would otherwise overwrite
+		// the Length and FragOffset we just set to describe the whole,
+		// unfragmented datagram - so IHL has to be set by hand too, since
+		// it's normally derived alongside Length. ComputeChecksums still
+		// recomputes the IP header checksum, which does need to match this
+		// fragment.
+		ipOpts := gopacket.SerializeOptions{FixLengths: false, ComputeChecksums: true}
+		if err := gopacket.SerializeLayers(buf, ipOpts, &frag, gopacket.Payload(chunk)); err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, buf.Bytes())
+	}
+
+	return fragments, nil
+}
+
+// sendFragments writes each pre-built IPv4 fragment to the network, always
+// hand-framed in Ethernet and written through the pcap handle - unlike
+// send, there's no raw-socket fallback for the on-link case. A stock
+// net.IPConn raw socket never sets IP_HDRINCL, so handing it our crafted,
+// fragmented IP header would just get the kernel's own (unfragmented)
+// header prepended in front of it as opaque payload; the pcap path is the
+// only way to put these bytes on the wire unmodified. destMAC is the
+// target's own MAC address when it's on-link, or the gateway's otherwise.
+func (s *Scanner) sendFragments(handle *pcap.Handle, destMAC net.HardwareAddr, fragments [][]byte) (int, error) {
+	total := 0
+	for _, frag := range fragments {
+		eth := layers.Ethernet{
+			SrcMAC:       s.networkInterface.HardwareAddr,
+			DstMAC:       destMAC,
+			EthernetType: layers.EthernetTypeIPv4,
+		}
+		buf := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+		if err := gopacket.SerializeLayers(buf, opts, &eth, gopacket.Payload(frag)); err != nil {
+			return total, err
+		}
+		if err := handle.WritePacketData(buf.Bytes()); err != nil {
+			return total, err
+		}
+		total += len(buf.Bytes())
+	}
+	return total, nil
+}
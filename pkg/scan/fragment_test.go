@@ -0,0 +1,79 @@
+package scan
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestBuildFragmentsOnWireLayout(t *testing.T) {
+	ip4 := layers.IPv4{
+		SrcIP:    net.ParseIP("192.0.2.1"),
+		DstIP:    net.ParseIP("192.0.2.2"),
+		Version:  4,
+		TTL:      255,
+		Id:       1234,
+		Protocol: layers.IPProtocolTCP,
+	}
+	tcp := layers.TCP{
+		SrcPort: 12345,
+		DstPort: 80,
+		SYN:     true,
+		Seq:     1,
+		Window:  1024,
+		Options: []layers.TCPOption{{
+			OptionType:   layers.TCPOptionKindMSS,
+			OptionLength: 4,
+			OptionData:   []byte{0x12, 0x34},
+		}},
+	}
+	tcp.SetNetworkLayerForChecksum(&ip4)
+
+	fragments, err := buildFragments(ip4, tcp, 28)
+	if err != nil {
+		t.Fatalf("buildFragments returned error: %v", err)
+	}
+	if len(fragments) < 2 {
+		t.Fatalf("expected at least 2 fragments at mtu 28, got %d", len(fragments))
+	}
+
+	var reassembled []byte
+	for i, frag := range fragments {
+		packet := gopacket.NewPacket(frag, layers.LayerTypeIPv4, gopacket.Default)
+		ipLayer := packet.Layer(layers.LayerTypeIPv4)
+		if ipLayer == nil {
+			t.Fatalf("fragment %d did not parse as IPv4", i)
+		}
+		parsed := ipLayer.(*layers.IPv4)
+
+		if parsed.Id != ip4.Id {
+			t.Errorf("fragment %d: Id = %d, want %d", i, parsed.Id, ip4.Id)
+		}
+		if int(parsed.Length) != len(frag) {
+			t.Errorf("fragment %d: Length = %d, want %d (actual wire size)", i, parsed.Length, len(frag))
+		}
+
+		wantMore := i < len(fragments)-1
+		gotMore := parsed.Flags&layers.IPv4MoreFragments != 0
+		if gotMore != wantMore {
+			t.Errorf("fragment %d: MoreFragments = %v, want %v", i, gotMore, wantMore)
+		}
+
+		wantOffset := len(reassembled) >> 3
+		if int(parsed.FragOffset) != wantOffset {
+			t.Errorf("fragment %d: FragOffset = %d, want %d", i, parsed.FragOffset, wantOffset)
+		}
+
+		reassembled = append(reassembled, parsed.Payload...)
+	}
+
+	tcpBuf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(tcpBuf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, &tcp); err != nil {
+		t.Fatalf("serializing reference TCP segment: %v", err)
+	}
+	if string(reassembled) != string(tcpBuf.Bytes()) {
+		t.Fatalf("reassembled fragments don't match the original TCP segment:\ngot:  %x\nwant: %x", reassembled, tcpBuf.Bytes())
+	}
+}
@@ -0,0 +1,284 @@
+package scan
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"github.com/phayes/freeport"
+
+	"github.com/projectdiscovery/naabu/pkg/log"
+	"github.com/projectdiscovery/naabu/pkg/routing"
+)
+
+// hostResult pairs a source host with a port classified on it, so a single
+// aggregator goroutine can demultiplex replies from every target without
+// one channel per host.
+type hostResult struct {
+	host string
+	port int
+}
+
+// hostState bundles the per-host values ScanHosts needs once routing has
+// been resolved for it: the source address to probe from, the gateway to
+// hand packets to when the host is off-link, and the host's own randomly
+// seeded sequence number for its probes. Replies are attributed to a host
+// by their source address alone; their acknowledgement number is not
+// checked against seq.
+type hostState struct {
+	ip      net.IP
+	srcIP   net.IP
+	gateway net.IP
+	gwMAC   net.HardwareAddr
+	seq     uint32
+}
+
+// ScanHosts scans wordlist against every host in hosts, sharing a single
+// pcap handle and a single global rate limit across all of them instead of
+// running Scan once per host. s.rate is therefore a total packets-per-second
+// budget, not a per-host one. All hosts are assumed reachable over the
+// interface s was constructed against; a target routed out a different
+// interface fails with ErrHostUnreachable rather than opening a second
+// handle for it.
+//
+// It only classifies SYN responses (Open on SYN/ACK); the stealth and ACK
+// modes' richer classification is left to the single-host Scan.
+func (s *Scanner) ScanHosts(hosts []net.IP, wordlist map[int]struct{}) (map[string]map[int]struct{}, error) {
+	router, err := routing.New()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]*hostState, len(hosts))
+	for _, host := range hosts {
+		route, err := router.Route(host)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrHostUnreachable, host, err)
+		}
+		if route.Interface.Name != s.networkInterface.Name {
+			return nil, fmt.Errorf("%w: %s routes out %s, not %s", ErrHostUnreachable, host, route.Interface.Name, s.networkInterface.Name)
+		}
+		states[host.String()] = &hostState{
+			ip:      host,
+			srcIP:   route.SrcIP,
+			gateway: route.Gateway,
+			seq:     uint32(1000000000 + rand.Intn(899999999)),
+		}
+	}
+
+	inactive, err := pcap.NewInactiveHandle(s.networkInterface.Name)
+	if err != nil {
+		return nil, err
+	}
+	inactive.SetSnapLen(65536)
+	if err := inactive.SetTimeout(1500 * time.Millisecond); err != nil {
+		inactive.CleanUp()
+		return nil, err
+	}
+	inactive.SetImmediateMode(true)
+
+	handle, err := inactive.Activate()
+	if err != nil {
+		inactive.CleanUp()
+		return nil, err
+	}
+	defer inactive.CleanUp()
+
+	rawPort, err := freeport.GetFreePort()
+	if err != nil {
+		handle.Close()
+		return nil, err
+	}
+
+	if err := handle.SetBPFFilter(fmt.Sprintf("tcp and dst port %d", rawPort)); err != nil {
+		handle.Close()
+		return nil, err
+	}
+
+	conn, err := net.ListenPacket("ip4:tcp", "0.0.0.0")
+	if err != nil {
+		handle.Close()
+		return nil, err
+	}
+
+	if s.arpResolver == nil {
+		s.arpResolver = routing.NewARPResolver()
+	}
+	for _, state := range states {
+		if state.gateway == nil {
+			continue
+		}
+		mac, err := s.arpResolver.Resolve(handle, s.networkInterface, state.gateway)
+		if err != nil {
+			handle.Close()
+			conn.Close()
+			return nil, fmt.Errorf("%w: %s", ErrHostUnreachable, err)
+		}
+		state.gwMAC = mac
+	}
+
+	resultChan := make(chan hostResult)
+	results := make(map[string]map[int]struct{}, len(hosts))
+	resultsWg := &sync.WaitGroup{}
+	resultsWg.Add(1)
+	go func() {
+		for r := range resultChan {
+			log.Debugf("Found active port %d on %s\n", r.port, r.host)
+			if results[r.host] == nil {
+				results[r.host] = make(map[int]struct{})
+			}
+			results[r.host][r.port] = struct{}{}
+		}
+		resultsWg.Done()
+	}()
+
+	tasksWg := &sync.WaitGroup{}
+	tasksWg.Add(1)
+	go func() {
+		var (
+			eth    layers.Ethernet
+			ip4    layers.IPv4
+			tcp    layers.TCP
+			parser *gopacket.DecodingLayerParser
+		)
+		if s.networkInterface.HardwareAddr != nil {
+			parser = gopacket.NewDecodingLayerParser(layers.LayerTypeEthernet, &eth, &ip4, &tcp)
+		} else {
+			parser = gopacket.NewDecodingLayerParser(layers.LayerTypeIPv4, &ip4, &tcp)
+		}
+
+		decoded := []gopacket.LayerType{}
+		for {
+			data, _, err := handle.ReadPacketData()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				continue
+			}
+			if err := parser.DecodeLayers(data, &decoded); err != nil {
+				continue
+			}
+
+			if result, ok := classifyMultiHostReply(states, rawPort, decoded, ip4, tcp); ok {
+				resultChan <- result
+			}
+		}
+		tasksWg.Done()
+	}()
+
+	limiter := time.Tick(time.Second / time.Duration(s.rate))
+
+	type probe struct {
+		host *hostState
+		port int
+	}
+	probes := make(chan probe)
+	go func() {
+		for p := range probes {
+			ip4 := layers.IPv4{SrcIP: p.host.srcIP, DstIP: p.host.ip, Version: 4, TTL: 255, Protocol: layers.IPProtocolTCP}
+			p.host.seq += 1 + uint32(rand.Intn(5))
+			tcp := layers.TCP{
+				SrcPort: layers.TCPPort(rawPort),
+				DstPort: layers.TCPPort(p.port),
+				SYN:     true,
+				Window:  1024,
+				Seq:     p.host.seq,
+			}
+			tcp.SetNetworkLayerForChecksum(&ip4)
+
+			s.sendWithRetries(limiter, func() (int, error) {
+				return sendToHost(conn, handle, s.networkInterface, p.host, &ip4, &tcp)
+			})
+		}
+	}()
+
+	for _, state := range states {
+		for port := range wordlist {
+			probes <- probe{host: state, port: port}
+		}
+	}
+	close(probes)
+
+	if s.timeout > 0 {
+		timer := time.AfterFunc(10*time.Second, func() {
+			handle.Close()
+			conn.Close()
+		})
+		defer timer.Stop()
+	} else {
+		handle.Close()
+		conn.Close()
+	}
+
+	tasksWg.Wait()
+	close(resultChan)
+	resultsWg.Wait()
+
+	return results, nil
+}
+
+// classifyMultiHostReply inspects one decoded packet's layers and reports
+// the hostResult it represents, if any: a SYN/ACK for rawPort from a
+// tracked host's source IP. It's split out of ScanHosts's read loop as a
+// pure function so the demultiplexing logic can be unit tested without a
+// live pcap handle.
+func classifyMultiHostReply(states map[string]*hostState, rawPort int, decoded []gopacket.LayerType, ip4 layers.IPv4, tcp layers.TCP) (hostResult, bool) {
+	var srcHost string
+	open := false
+	var openPort int
+	for _, layerType := range decoded {
+		switch layerType {
+		case layers.LayerTypeIPv4:
+			if _, tracked := states[ip4.SrcIP.String()]; !tracked {
+				srcHost = ""
+				continue
+			}
+			srcHost = ip4.SrcIP.String()
+		case layers.LayerTypeTCP:
+			if tcp.DstPort != layers.TCPPort(rawPort) {
+				continue
+			}
+			if tcp.SYN && tcp.ACK {
+				open = true
+				openPort = int(tcp.SrcPort)
+			}
+		}
+	}
+	if open && srcHost != "" {
+		return hostResult{host: srcHost, port: openPort}, true
+	}
+	return hostResult{}, false
+}
+
+// sendToHost writes a single TCP segment to host, either straight to conn
+// on a directly connected subnet or, for an off-link host, framed in
+// Ethernet to its resolved gateway MAC through handle - the same choice
+// Scanner.send makes, generalized to a caller-supplied host instead of
+// s.host/s.gateway.
+func sendToHost(conn net.PacketConn, handle *pcap.Handle, iface *net.Interface, host *hostState, ip4 *layers.IPv4, tcp *layers.TCP) (int, error) {
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+
+	if host.gateway == nil {
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, opts, tcp); err != nil {
+			return 0, err
+		}
+		return conn.WriteTo(buf.Bytes(), &net.IPAddr{IP: host.ip})
+	}
+
+	eth := layers.Ethernet{SrcMAC: iface.HardwareAddr, DstMAC: host.gwMAC, EthernetType: layers.EthernetTypeIPv4}
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, opts, &eth, ip4, tcp); err != nil {
+		return 0, err
+	}
+	if err := handle.WritePacketData(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(buf.Bytes()), nil
+}
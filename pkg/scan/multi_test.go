@@ -0,0 +1,63 @@
+package scan
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestClassifyMultiHostReply(t *testing.T) {
+	states := map[string]*hostState{
+		"192.0.2.1": {ip: net.ParseIP("192.0.2.1")},
+		"192.0.2.2": {ip: net.ParseIP("192.0.2.2")},
+	}
+	const rawPort = 54321
+
+	tests := []struct {
+		name       string
+		srcIP      string
+		dstPort    layers.TCPPort
+		syn, ack   bool
+		wantHost   string
+		wantPort   int
+		wantResult bool
+	}{
+		{
+			name: "tracked host SYN/ACK reports open", srcIP: "192.0.2.1", dstPort: rawPort,
+			syn: true, ack: true, wantHost: "192.0.2.1", wantPort: 80, wantResult: true,
+		},
+		{
+			name: "untracked host is ignored even with SYN/ACK", srcIP: "198.51.100.1", dstPort: rawPort,
+			syn: true, ack: true, wantResult: false,
+		},
+		{
+			name: "tracked host but wrong dst port is ignored", srcIP: "192.0.2.2", dstPort: rawPort + 1,
+			syn: true, ack: true, wantResult: false,
+		},
+		{
+			name: "tracked host RST only is not open", srcIP: "192.0.2.2", dstPort: rawPort,
+			syn: false, ack: false, wantResult: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip4 := layers.IPv4{SrcIP: net.ParseIP(tt.srcIP)}
+			tcp := layers.TCP{SrcPort: 80, DstPort: tt.dstPort, SYN: tt.syn, ACK: tt.ack}
+			decoded := []gopacket.LayerType{layers.LayerTypeIPv4, layers.LayerTypeTCP}
+
+			result, ok := classifyMultiHostReply(states, rawPort, decoded, ip4, tcp)
+			if ok != tt.wantResult {
+				t.Fatalf("classifyMultiHostReply() ok = %v, want %v", ok, tt.wantResult)
+			}
+			if !ok {
+				return
+			}
+			if result.host != tt.wantHost || result.port != tt.wantPort {
+				t.Fatalf("classifyMultiHostReply() = %+v, want host=%s port=%d", result, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package scan
+
+// ScanMode selects which combination of TCP flags Scanner.Scan sets on its
+// probe and, consequently, how the response is interpreted.
+type ScanMode int
+
+const (
+	// ScanModeSYN is the default half-open scan: SYN only.
+	ScanModeSYN ScanMode = iota
+	// ScanModeFIN sets only FIN.
+	ScanModeFIN
+	// ScanModeNULL sets no flags at all.
+	ScanModeNULL
+	// ScanModeXMAS sets FIN, PSH and URG.
+	ScanModeXMAS
+	// ScanModeACK sets only ACK, used to map firewall rulesets rather
+	// than to find open ports.
+	ScanModeACK
+)
+
+// Option configures a Scanner at construction time.
+type Option func(*Scanner)
+
+// WithMode overrides the default SYN scan with one of the stealth variants
+// or an ACK scan.
+func WithMode(mode ScanMode) Option {
+	return func(s *Scanner) {
+		s.Mode = mode
+	}
+}
+
+// WithFragment splits each SYN probe across multiple IPv4 fragments to
+// evade stateless packet filters that only inspect the first fragment. mtu
+// bounds the size of each fragment; pass 0 to get nmap -f's 8-byte
+// fragments. This only affects IPv4 targets.
+func WithFragment(mtu int) Option {
+	return func(s *Scanner) {
+		s.Fragment = true
+		s.FragmentMTU = mtu
+	}
+}
+
+// WithGrab marks a Scanner for follow-up banner grabbing: callers that
+// check s.Grab after Scan should feed its open ports to GrabBanners (or
+// GrabBannersPcap for many ports at once).
+func WithGrab() Option {
+	return func(s *Scanner) {
+		s.Grab = true
+	}
+}
@@ -0,0 +1,44 @@
+package scan
+
+// PortState is the classification a scan assigns to a probed port. Which
+// states a given ScanMode can actually produce depends on the probe: a SYN
+// scan only ever reports Open or Closed, while the stealth (FIN/NULL/XMAS)
+// and ACK scans can additionally report OpenFiltered and Unfiltered per the
+// RFC 793 response rules they rely on.
+type PortState int
+
+const (
+	// Open means the target actively acknowledged the probe (SYN/ACK).
+	Open PortState = iota
+	// Closed means the target replied with RST.
+	Closed
+	// Filtered means a firewall is dropping probes or replying with an
+	// ICMP unreachable, and the true state can't be determined.
+	Filtered
+	// OpenFiltered means the probe drew no response at all, which for a
+	// FIN/NULL/XMAS scan is consistent with either an open port or a
+	// packet filter silently dropping it - RFC 793 doesn't let us tell
+	// the two apart.
+	OpenFiltered
+	// Unfiltered means an ACK probe drew an RST, i.e. the port is
+	// reachable through whatever's in front of it, without indicating
+	// whether the port itself is open or closed.
+	Unfiltered
+)
+
+func (p PortState) String() string {
+	switch p {
+	case Open:
+		return "open"
+	case Closed:
+		return "closed"
+	case Filtered:
+		return "filtered"
+	case OpenFiltered:
+		return "open|filtered"
+	case Unfiltered:
+		return "unfiltered"
+	default:
+		return "unknown"
+	}
+}
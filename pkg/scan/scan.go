@@ -5,7 +5,6 @@ import (
 	"io"
 	"math/rand"
 	"net"
-	"strings"
 	"sync"
 	"time"
 
@@ -15,22 +14,44 @@ import (
 	"github.com/phayes/freeport"
 
 	"github.com/projectdiscovery/naabu/pkg/log"
+	"github.com/projectdiscovery/naabu/pkg/routing"
 )
 
-// Scanner is a scanner that scans for ports using SYN packets.
+// Scanner is a scanner that scans for ports using SYN packets by default,
+// or one of the stealth/ACK variants selected via WithMode.
 type Scanner struct {
 	timeout          time.Duration
 	serializeOptions gopacket.SerializeOptions
 	retries          int
 	rate             int
 
+	// Mode selects the TCP flag combination used for the probe and,
+	// correspondingly, how the response classifies the port.
+	Mode ScanMode
+
+	// Fragment splits each SYN probe's TCP header across multiple IPv4
+	// fragments (see WithFragment) instead of sending it whole.
+	Fragment    bool
+	FragmentMTU int
+
+	// Grab tells callers that own a Scanner's open-port results that they
+	// should follow up with GrabBanners; Scan itself doesn't read it, since
+	// grabbing happens after a port is already known to be open.
+	Grab bool
+
 	networkInterface *net.Interface
 	host             net.IP
 	srcIP            net.IP
+
+	// gateway is the next-hop address packets to host must be handed to,
+	// nil when host is on a directly connected subnet.
+	gateway     net.IP
+	gatewayMAC  net.HardwareAddr
+	arpResolver *routing.ARPResolver
 }
 
 // NewScanner creates a new full port scanner that scans all ports using SYN packets.
-func NewScanner(host net.IP, timeout time.Duration, retries, rate int) (*Scanner, error) {
+func NewScanner(host net.IP, timeout time.Duration, retries, rate int, opts ...Option) (*Scanner, error) {
 	rand.Seed(time.Now().UnixNano())
 
 	scanner := &Scanner{
@@ -41,36 +62,137 @@ func NewScanner(host net.IP, timeout time.Duration, retries, rate int) (*Scanner
 		timeout: timeout,
 		retries: retries,
 		rate:    rate,
+		Mode:    ScanModeSYN,
 
 		host: host,
 	}
 
-	// Get the source IP and the network interface packets will be sent from
-	var err error
-	scanner.srcIP, err = getSourceIP(host)
+	for _, opt := range opts {
+		opt(scanner)
+	}
+
+	router, err := routing.New()
 	if err != nil {
 		return nil, err
 	}
 
-	scanner.networkInterface, err = getInterfaceFromIP(scanner.srcIP)
+	route, err := router.Route(host)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %s", ErrHostUnreachable, err)
 	}
 
+	scanner.srcIP = route.SrcIP
+	scanner.networkInterface = route.Interface
+	scanner.gateway = route.Gateway
+	scanner.arpResolver = routing.NewARPResolver()
+
 	return scanner, nil
 }
 
-// send sends the given layers as a single packet on the network.
-func (s *Scanner) send(conn net.PacketConn, l ...gopacket.SerializableLayer) (int, error) {
+// send sends the given transport-layer payload as a single packet on the
+// network. On a directly connected subnet it writes straight to conn and
+// lets the kernel fill in the IP header; for an off-link host there is no
+// such socket to lean on, so it instead serializes networkLayer itself
+// alongside an Ethernet header addressed to the resolved gateway MAC and
+// writes the whole frame through the pcap handle.
+func (s *Scanner) send(conn net.PacketConn, handle *pcap.Handle, networkLayer gopacket.SerializableLayer, l ...gopacket.SerializableLayer) (int, error) {
+	if s.gateway == nil {
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, s.serializeOptions, l...); err != nil {
+			return 0, err
+		}
+		return conn.WriteTo(buf.Bytes(), &net.IPAddr{IP: s.host})
+	}
+
+	ethType := layers.EthernetTypeIPv4
+	if s.host.To4() == nil {
+		ethType = layers.EthernetTypeIPv6
+	}
+	eth := layers.Ethernet{
+		SrcMAC:       s.networkInterface.HardwareAddr,
+		DstMAC:       s.gatewayMAC,
+		EthernetType: ethType,
+	}
 	buf := gopacket.NewSerializeBuffer()
-	if err := gopacket.SerializeLayers(buf, s.serializeOptions, l...); err != nil {
+	ethLayers := append([]gopacket.SerializableLayer{&eth, networkLayer}, l...)
+	if err := gopacket.SerializeLayers(buf, s.serializeOptions, ethLayers...); err != nil {
+		return 0, err
+	}
+	if err := handle.WritePacketData(buf.Bytes()); err != nil {
 		return 0, err
 	}
-	return conn.WriteTo(buf.Bytes(), &net.IPAddr{IP: s.host})
+	return len(buf.Bytes()), nil
 }
 
-// Scan scans a single host and returns the results
-func (s *Scanner) Scan(wordlist map[int]struct{}) (map[int]struct{}, error) {
+// sendWithRetries calls send, rate-limited by limiter, up to s.retries
+// times, stopping as soon as a call reports bytes written with no error.
+// It is shared between the SYN and UDP scan loops so both get the same
+// rate-limiting and retry semantics.
+func (s *Scanner) sendWithRetries(limiter <-chan time.Time, send func() (int, error)) {
+	for i := 0; i < s.retries; i++ {
+		<-limiter
+		if n, err := send(); n > 0 && err == nil {
+			break
+		}
+	}
+}
+
+// portResult pairs a port with the state a probe response (or its absence)
+// assigned it.
+type portResult struct {
+	port  int
+	state PortState
+}
+
+// classifyResponse interprets an incoming TCP segment for our probe's
+// source port according to the RFC 793 rules for mode, returning ok=false
+// when the segment itself carries no classifying information (in which
+// case the port's state is instead decided by the default applied after
+// the scan drains, see defaultState).
+func classifyResponse(mode ScanMode, tcp layers.TCP) (PortState, bool) {
+	switch mode {
+	case ScanModeACK:
+		if tcp.RST {
+			return Unfiltered, true
+		}
+		return Filtered, false
+	case ScanModeFIN, ScanModeNULL, ScanModeXMAS:
+		if tcp.RST {
+			return Closed, true
+		}
+		return OpenFiltered, false
+	default: // ScanModeSYN
+		if tcp.SYN && tcp.ACK {
+			return Open, true
+		}
+		if tcp.RST {
+			return Closed, true
+		}
+		return Closed, false
+	}
+}
+
+// defaultState is the state assigned to a port that received no
+// classifying response at all once the scan finishes draining.
+func defaultState(mode ScanMode) (state PortState, report bool) {
+	switch mode {
+	case ScanModeFIN, ScanModeNULL, ScanModeXMAS:
+		return OpenFiltered, true
+	case ScanModeACK:
+		return Filtered, true
+	default: // ScanModeSYN
+		return Closed, false
+	}
+}
+
+// Scan scans a single host for the ports in wordlist and returns each port's
+// classified PortState. Which states are reachable depends on s.Mode: a SYN
+// scan only ever reports Open or Closed; the FIN/NULL/XMAS and ACK variants
+// follow the RFC 793 rules described on PortState and can additionally
+// report OpenFiltered or Unfiltered.
+func (s *Scanner) Scan(wordlist map[int]struct{}) (map[int]PortState, error) {
+	isIPv6 := s.host.To4() == nil
+
 	inactive, err := pcap.NewInactiveHandle(s.networkInterface.Name)
 	if err != nil {
 		return nil, err
@@ -97,38 +219,78 @@ func (s *Scanner) Scan(wordlist map[int]struct{}) (map[int]struct{}, error) {
 		return nil, err
 	}
 
-	// Strict BPF filter
-	// + Packets coming from target ip
-	// + Destination port equals to sender socket source port
-	err = handle.SetBPFFilter(fmt.Sprintf("tcp and port %d and ip host %s", rawPort, s.host))
+	if s.gateway != nil {
+		s.gatewayMAC, err = s.arpResolver.Resolve(handle, s.networkInterface, s.gateway)
+		if err != nil {
+			handle.Close()
+			inactive.CleanUp()
+			return nil, fmt.Errorf("%w: %s", ErrHostUnreachable, err)
+		}
+	}
+
+	// Fragments are always hand-framed in Ethernet and written through
+	// handle (see sendFragments): a stock net.IPConn raw socket never sets
+	// IP_HDRINCL, so the kernel would prepend its own IP header in front of
+	// our crafted one instead of transmitting it, even on-link. That means
+	// on-link fragmenting needs the target's own MAC, not just the
+	// gateway's.
+	var fragmentDestMAC net.HardwareAddr
+	if s.Fragment && !isIPv6 {
+		fragmentDestMAC = s.gatewayMAC
+		if s.gateway == nil {
+			fragmentDestMAC, err = s.arpResolver.Resolve(handle, s.networkInterface, s.host)
+			if err != nil {
+				handle.Close()
+				inactive.CleanUp()
+				return nil, fmt.Errorf("%w: %s", ErrHostUnreachable, err)
+			}
+		}
+	}
+
+	// BPF filter: packets coming from the target, destined for our probe's
+	// source port, widened to let RSTs and the ICMP unreachable codes the
+	// stealth/ACK classifiers rely on (1 host, 2 protocol, 3 port, 9 admin
+	// prohibited, 10 host admin prohibited, 13 communication prohibited)
+	// through as well.
+	icmpCodes := "(icmp[1]=1 or icmp[1]=2 or icmp[1]=3 or icmp[1]=9 or icmp[1]=10 or icmp[1]=13)"
+	bpfFilter := fmt.Sprintf("(tcp and port %d and ip host %s) or (icmp and icmp[0]=3 and %s)", rawPort, s.host, icmpCodes)
+	rawNetwork := "ip4:tcp"
+	listenAddr := "0.0.0.0"
+	if isIPv6 {
+		bpfFilter = fmt.Sprintf("(tcp and port %d and ip6 host %s) or (icmp6 and icmp6[0]=1)", rawPort, s.host)
+		rawNetwork = "ip6:tcp"
+		listenAddr = "::"
+	}
+	err = handle.SetBPFFilter(bpfFilter)
 	if err != nil {
 		handle.Close()
 		inactive.CleanUp()
 		return nil, err
 	}
 
-	conn, err := net.ListenPacket("ip4:tcp", "0.0.0.0")
+	conn, err := net.ListenPacket(rawNetwork, listenAddr)
 	if err != nil {
 		handle.Close()
 		inactive.CleanUp()
 		return nil, err
 	}
 
-	openChan := make(chan int)
-	results := make(map[int]struct{})
+	resultChan := make(chan portResult)
+	results := make(map[int]PortState)
 	resultsWg := &sync.WaitGroup{}
 	resultsWg.Add(1)
 
 	go func() {
-		for open := range openChan {
-			log.Debugf("Found active port %d on %s\n", open, s.host.String())
+		for r := range resultChan {
+			log.Debugf("Port %d on %s is %s\n", r.port, s.host.String(), r.state)
 
-			results[open] = struct{}{}
+			results[r.port] = r.state
 		}
 		resultsWg.Done()
 	}()
 
 	// Construct all the network layers we need.
+	var networkLayer gopacket.SerializableLayer
 	ip4 := layers.IPv4{
 		SrcIP:    s.srcIP,
 		DstIP:    s.host,
@@ -136,6 +298,19 @@ func (s *Scanner) Scan(wordlist map[int]struct{}) (map[int]struct{}, error) {
 		TTL:      255,
 		Protocol: layers.IPProtocolTCP,
 	}
+	ip6 := layers.IPv6{
+		SrcIP:      s.srcIP,
+		DstIP:      s.host,
+		Version:    6,
+		HopLimit:   64,
+		NextHeader: layers.IPProtocolTCP,
+	}
+	if isIPv6 {
+		networkLayer = &ip6
+	} else {
+		networkLayer = &ip4
+	}
+
 	tcpOption := layers.TCPOption{
 		OptionType:   layers.TCPOptionKindMSS,
 		OptionLength: 4,
@@ -146,31 +321,58 @@ func (s *Scanner) Scan(wordlist map[int]struct{}) (map[int]struct{}, error) {
 	tcp := layers.TCP{
 		SrcPort: layers.TCPPort(rawPort),
 		DstPort: 0,
-		SYN:     true,
 		Window:  1024,
 		Seq:     uint32(randSeq),
 		Options: []layers.TCPOption{tcpOption},
 	}
-	tcp.SetNetworkLayerForChecksum(&ip4)
+	switch s.Mode {
+	case ScanModeFIN:
+		tcp.FIN = true
+	case ScanModeNULL:
+		// No flags set.
+	case ScanModeXMAS:
+		tcp.FIN, tcp.PSH, tcp.URG = true, true, true
+	case ScanModeACK:
+		tcp.ACK = true
+	default:
+		tcp.SYN = true
+	}
+	if isIPv6 {
+		tcp.SetNetworkLayerForChecksum(&ip6)
+	} else {
+		tcp.SetNetworkLayerForChecksum(&ip4)
+	}
 
 	tasksWg := &sync.WaitGroup{}
 	tasksWg.Add(1)
-	ipFlow := gopacket.NewFlow(layers.EndpointIPv4, s.host, s.srcIP)
+	endpointType := layers.EndpointIPv4
+	if isIPv6 {
+		endpointType = layers.EndpointIPv6
+	}
+	ipFlow := gopacket.NewFlow(endpointType, s.host, s.srcIP)
 
 	go func() {
 		var (
 			eth    layers.Ethernet
 			ip4    layers.IPv4
+			ip6    layers.IPv6
 			tcp    layers.TCP
+			icmp4  layers.ICMPv4
+			icmp6  layers.ICMPv6
 			parser *gopacket.DecodingLayerParser
 		)
 
+		networkLayerType := layers.LayerTypeIPv4
+		if isIPv6 {
+			networkLayerType = layers.LayerTypeIPv6
+		}
+
 		if s.networkInterface.HardwareAddr != nil {
 			// Interfaces with MAC (Physical + Virtualized)
-			parser = gopacket.NewDecodingLayerParser(layers.LayerTypeEthernet, &eth, &ip4, &tcp)
+			parser = gopacket.NewDecodingLayerParser(layers.LayerTypeEthernet, &eth, &ip4, &ip6, &tcp, &icmp4, &icmp6)
 		} else {
 			// Interfaces without MAC (TUN/TAP)
-			parser = gopacket.NewDecodingLayerParser(layers.LayerTypeIPv4, &ip4, &tcp)
+			parser = gopacket.NewDecodingLayerParser(networkLayerType, &ip4, &ip6, &tcp, &icmp4, &icmp6)
 		}
 
 		decoded := []gopacket.LayerType{}
@@ -191,13 +393,26 @@ func (s *Scanner) Scan(wordlist map[int]struct{}) (map[int]struct{}, error) {
 					if ip4.NetworkFlow() != ipFlow {
 						continue
 					}
+				case layers.LayerTypeIPv6:
+					if ip6.NetworkFlow() != ipFlow {
+						continue
+					}
 				case layers.LayerTypeTCP:
 					// We consider only incoming packets
 					if tcp.DstPort != layers.TCPPort(rawPort) {
 						continue
-					} else if tcp.SYN && tcp.ACK {
-						openChan <- int(tcp.SrcPort)
 					}
+					if state, ok := classifyResponse(s.Mode, tcp); ok {
+						resultChan <- portResult{port: int(tcp.SrcPort), state: state}
+					}
+				case layers.LayerTypeICMPv4, layers.LayerTypeICMPv6:
+					// A Destination/Port/Admin-Prohibited unreachable for
+					// our probe tells us the path is filtered; since the
+					// ICMP payload echoes the original datagram, mapping
+					// it back to the exact port probed would need parsing
+					// that embedded header; we don't need ICMP for SYN or
+					// FIN/NULL/XMAS classification so it's only consumed
+					// here to keep the parser from choking on it.
 				}
 			}
 		}
@@ -215,13 +430,22 @@ func (s *Scanner) Scan(wordlist map[int]struct{}) (map[int]struct{}, error) {
 			randSeq += 1 + rand.Intn(5)
 			tcp.Seq = uint32(randSeq)
 			tcp.DstPort = layers.TCPPort(port)
-			for i := 0; i < s.retries; i++ {
-				<-limiter
-				n, err := s.send(conn, &tcp)
-				if n > 0 && err == nil {
-					break
+
+			if s.Fragment && !isIPv6 {
+				ip4.Id = uint16(rand.Intn(65536))
+				fragments, err := buildFragments(ip4, tcp, s.FragmentMTU)
+				if err != nil {
+					continue
 				}
+				s.sendWithRetries(limiter, func() (int, error) {
+					return s.sendFragments(handle, fragmentDestMAC, fragments)
+				})
+				continue
 			}
+
+			s.sendWithRetries(limiter, func() (int, error) {
+				return s.send(conn, handle, networkLayer, &tcp)
+			})
 		}
 	}()
 
@@ -243,53 +467,18 @@ func (s *Scanner) Scan(wordlist map[int]struct{}) (map[int]struct{}, error) {
 	}
 
 	tasksWg.Wait()
-	close(openChan)
+	close(resultChan)
 	resultsWg.Wait()
 
 	inactive.CleanUp()
 
-	return results, nil
-}
-
-// getSourceIP gets the local ip based on our destination ip
-func getSourceIP(dstip net.IP) (net.IP, error) {
-	serverAddr, err := net.ResolveUDPAddr("udp", dstip.String()+":12345")
-	if err != nil {
-		return nil, err
-	}
-
-	if con, err := net.DialUDP("udp", nil, serverAddr); err == nil {
-		defer con.Close()
-		if udpaddr, ok := con.LocalAddr().(*net.UDPAddr); ok {
-			return udpaddr.IP, nil
-		}
-	}
-	return nil, err
-}
-
-// getInterfaceFromIP gets the name of the network interface from local ip address
-func getInterfaceFromIP(ip net.IP) (*net.Interface, error) {
-	address := ip.String()
-
-	interfaces, err := net.Interfaces()
-	if err != nil {
-		return nil, err
-	}
-
-	for _, i := range interfaces {
-		byNameInterface, err := net.InterfaceByName(i.Name)
-		if err != nil {
-			return nil, err
-		}
-		addresses, err := byNameInterface.Addrs()
-		for _, v := range addresses {
-			// Check if the IP for the current interface is our
-			// source IP. If yes, return the interface
-			if strings.HasPrefix(v.String(), address+"/") {
-				return byNameInterface, nil
+	if state, report := defaultState(s.Mode); report {
+		for port := range wordlist {
+			if _, ok := results[port]; !ok {
+				results[port] = state
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("no interface found for ip %s", address)
+	return results, nil
 }
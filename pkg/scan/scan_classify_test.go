@@ -0,0 +1,62 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/google/gopacket/layers"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      ScanMode
+		tcp       layers.TCP
+		wantState PortState
+		wantOK    bool
+	}{
+		{name: "SYN: SYN/ACK is open", mode: ScanModeSYN, tcp: layers.TCP{SYN: true, ACK: true}, wantState: Open, wantOK: true},
+		{name: "SYN: RST is closed", mode: ScanModeSYN, tcp: layers.TCP{RST: true}, wantState: Closed, wantOK: true},
+		{name: "SYN: anything else is an unreported closed default", mode: ScanModeSYN, tcp: layers.TCP{ACK: true}, wantState: Closed, wantOK: false},
+
+		{name: "FIN: RST is closed", mode: ScanModeFIN, tcp: layers.TCP{RST: true}, wantState: Closed, wantOK: true},
+		{name: "FIN: no RST is an unreported open|filtered default", mode: ScanModeFIN, tcp: layers.TCP{ACK: true}, wantState: OpenFiltered, wantOK: false},
+		{name: "NULL: RST is closed", mode: ScanModeNULL, tcp: layers.TCP{RST: true}, wantState: Closed, wantOK: true},
+		{name: "XMAS: RST is closed", mode: ScanModeXMAS, tcp: layers.TCP{RST: true}, wantState: Closed, wantOK: true},
+
+		{name: "ACK: RST is unfiltered", mode: ScanModeACK, tcp: layers.TCP{RST: true}, wantState: Unfiltered, wantOK: true},
+		{name: "ACK: no RST is an unreported filtered default", mode: ScanModeACK, tcp: layers.TCP{}, wantState: Filtered, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, ok := classifyResponse(tt.mode, tt.tcp)
+			if state != tt.wantState || ok != tt.wantOK {
+				t.Fatalf("classifyResponse(%v, %+v) = (%v, %v), want (%v, %v)", tt.mode, tt.tcp, state, ok, tt.wantState, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDefaultState(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       ScanMode
+		wantState  PortState
+		wantReport bool
+	}{
+		{name: "SYN defaults to closed, unreported", mode: ScanModeSYN, wantState: Closed, wantReport: false},
+		{name: "FIN defaults to open|filtered, reported", mode: ScanModeFIN, wantState: OpenFiltered, wantReport: true},
+		{name: "NULL defaults to open|filtered, reported", mode: ScanModeNULL, wantState: OpenFiltered, wantReport: true},
+		{name: "XMAS defaults to open|filtered, reported", mode: ScanModeXMAS, wantState: OpenFiltered, wantReport: true},
+		{name: "ACK defaults to filtered, reported", mode: ScanModeACK, wantState: Filtered, wantReport: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, report := defaultState(tt.mode)
+			if state != tt.wantState || report != tt.wantReport {
+				t.Fatalf("defaultState(%v) = (%v, %v), want (%v, %v)", tt.mode, state, report, tt.wantState, tt.wantReport)
+			}
+		})
+	}
+}
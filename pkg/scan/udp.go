@@ -0,0 +1,260 @@
+package scan
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"github.com/phayes/freeport"
+
+	"github.com/projectdiscovery/naabu/pkg/log"
+)
+
+// udpProbes carries small protocol-specific payloads for ports that won't
+// otherwise reply to an empty datagram, increasing the odds of eliciting a
+// response from the service listening there.
+var udpProbes = map[int][]byte{
+	53:   {0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, // minimal DNS query header
+	123:  append([]byte{0x1b}, make([]byte, 47)...),                                // NTP client request
+	161: {0x30, 0x26, 0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c', // SNMP GetRequest (community "public")
+		0xa0, 0x19, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00, // for sysDescr.0
+		0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00},
+	1900: []byte("M-SEARCH * HTTP/1.1\r\nHOST:239.255.255.250:1900\r\nMAN:\"ssdp:discover\"\r\nMX:1\r\nST:ssdp:all\r\n\r\n"),
+}
+
+// ScanUDP probes wordlist over UDP and classifies each port: a UDP reply
+// marks it Open, an ICMP Destination/Port Unreachable marks it Closed, and
+// a port that draws neither before the scan stops reading is reported
+// OpenFiltered, since open and filtered are indistinguishable without
+// further probing.
+func (s *Scanner) ScanUDP(wordlist map[int]struct{}) (map[int]PortState, error) {
+	isIPv6 := s.host.To4() == nil
+
+	inactive, err := pcap.NewInactiveHandle(s.networkInterface.Name)
+	if err != nil {
+		return nil, err
+	}
+	inactive.SetSnapLen(65536)
+
+	readTimeout := time.Duration(1500) * time.Millisecond
+	if err = inactive.SetTimeout(readTimeout); err != nil {
+		inactive.CleanUp()
+		return nil, err
+	}
+	inactive.SetImmediateMode(true)
+
+	handle, err := inactive.Activate()
+	if err != nil {
+		inactive.CleanUp()
+		return nil, err
+	}
+
+	rawPort, err := freeport.GetFreePort()
+	if err != nil {
+		handle.Close()
+		inactive.CleanUp()
+		return nil, err
+	}
+
+	if s.gateway != nil {
+		s.gatewayMAC, err = s.arpResolver.Resolve(handle, s.networkInterface, s.gateway)
+		if err != nil {
+			handle.Close()
+			inactive.CleanUp()
+			return nil, fmt.Errorf("%w: %s", ErrHostUnreachable, err)
+		}
+	}
+
+	bpfFilter := fmt.Sprintf("(udp and port %d and ip host %s) or (icmp and icmp[0]=3)", rawPort, s.host)
+	rawNetwork := "ip4:udp"
+	listenAddr := "0.0.0.0"
+	if isIPv6 {
+		bpfFilter = fmt.Sprintf("(udp and port %d and ip6 host %s) or (icmp6 and icmp6[0]=1)", rawPort, s.host)
+		rawNetwork = "ip6:udp"
+		listenAddr = "::"
+	}
+	if err = handle.SetBPFFilter(bpfFilter); err != nil {
+		handle.Close()
+		inactive.CleanUp()
+		return nil, err
+	}
+
+	conn, err := net.ListenPacket(rawNetwork, listenAddr)
+	if err != nil {
+		handle.Close()
+		inactive.CleanUp()
+		return nil, err
+	}
+
+	resultChan := make(chan portResult)
+	results := make(map[int]PortState)
+	resultsWg := &sync.WaitGroup{}
+	resultsWg.Add(1)
+
+	go func() {
+		for r := range resultChan {
+			log.Debugf("UDP port %d on %s is %s\n", r.port, s.host.String(), r.state)
+			results[r.port] = r.state
+		}
+		resultsWg.Done()
+	}()
+
+	var networkLayer gopacket.SerializableLayer
+	ip4 := layers.IPv4{SrcIP: s.srcIP, DstIP: s.host, Version: 4, TTL: 255, Protocol: layers.IPProtocolUDP}
+	ip6 := layers.IPv6{SrcIP: s.srcIP, DstIP: s.host, Version: 6, HopLimit: 64, NextHeader: layers.IPProtocolUDP}
+	if isIPv6 {
+		networkLayer = &ip6
+	} else {
+		networkLayer = &ip4
+	}
+
+	udp := layers.UDP{SrcPort: layers.UDPPort(rawPort)}
+	if isIPv6 {
+		udp.SetNetworkLayerForChecksum(&ip6)
+	} else {
+		udp.SetNetworkLayerForChecksum(&ip4)
+	}
+
+	tasksWg := &sync.WaitGroup{}
+	tasksWg.Add(1)
+	endpointType := layers.EndpointIPv4
+	if isIPv6 {
+		endpointType = layers.EndpointIPv6
+	}
+	ipFlow := gopacket.NewFlow(endpointType, s.host, s.srcIP)
+
+	go func() {
+		var (
+			eth    layers.Ethernet
+			ip4    layers.IPv4
+			ip6    layers.IPv6
+			udp    layers.UDP
+			icmp4  layers.ICMPv4
+			icmp6  layers.ICMPv6
+			parser *gopacket.DecodingLayerParser
+		)
+
+		networkLayerType := layers.LayerTypeIPv4
+		if isIPv6 {
+			networkLayerType = layers.LayerTypeIPv6
+		}
+
+		if s.networkInterface.HardwareAddr != nil {
+			parser = gopacket.NewDecodingLayerParser(layers.LayerTypeEthernet, &eth, &ip4, &ip6, &udp, &icmp4, &icmp6)
+		} else {
+			parser = gopacket.NewDecodingLayerParser(networkLayerType, &ip4, &ip6, &udp, &icmp4, &icmp6)
+		}
+
+		decoded := []gopacket.LayerType{}
+		for {
+			data, _, err := handle.ReadPacketData()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				continue
+			}
+
+			if err := parser.DecodeLayers(data, &decoded); err != nil {
+				continue
+			}
+			for _, layerType := range decoded {
+				switch layerType {
+				case layers.LayerTypeIPv4:
+					if ip4.NetworkFlow() != ipFlow {
+						continue
+					}
+				case layers.LayerTypeIPv6:
+					if ip6.NetworkFlow() != ipFlow {
+						continue
+					}
+				case layers.LayerTypeUDP:
+					if udp.DstPort == layers.UDPPort(rawPort) {
+						resultChan <- portResult{port: int(udp.SrcPort), state: Open}
+					}
+				case layers.LayerTypeICMPv4:
+					// The ICMP payload echoes the original IP+UDP datagram,
+					// which is the only way to learn which of our in-flight
+					// probes a Destination/Port Unreachable is for.
+					if icmp4.TypeCode != layers.CreateICMPv4TypeCode(layers.ICMPv4TypeDestinationUnreachable, layers.ICMPv4CodePort) {
+						continue
+					}
+					if port, ok := icmpUnreachablePort(false, icmp4.Payload); ok {
+						resultChan <- portResult{port: port, state: Closed}
+					}
+				case layers.LayerTypeICMPv6:
+					if icmp6.TypeCode != layers.CreateICMPv6TypeCode(layers.ICMPv6TypeDestinationUnreachable, layers.ICMPv6CodePortUnreachable) {
+						continue
+					}
+					if port, ok := icmpUnreachablePort(true, icmp6.Payload); ok {
+						resultChan <- portResult{port: port, state: Closed}
+					}
+				}
+			}
+		}
+		tasksWg.Done()
+	}()
+
+	limiter := time.Tick(time.Second / time.Duration(s.rate))
+
+	ports := make(chan int)
+	go func() {
+		for port := range ports {
+			udp.DstPort = layers.UDPPort(port)
+			payload := gopacket.Payload(udpProbes[port])
+			s.sendWithRetries(limiter, func() (int, error) {
+				return s.send(conn, handle, networkLayer, &udp, payload)
+			})
+		}
+	}()
+
+	for port := range wordlist {
+		ports <- port
+	}
+	close(ports)
+
+	if s.timeout > 0 {
+		timer := time.AfterFunc(10*time.Second, func() {
+			handle.Close()
+			conn.Close()
+		})
+		defer timer.Stop()
+	} else {
+		handle.Close()
+		conn.Close()
+	}
+
+	tasksWg.Wait()
+	close(resultChan)
+	resultsWg.Wait()
+
+	inactive.CleanUp()
+
+	for port := range wordlist {
+		if _, ok := results[port]; !ok {
+			results[port] = OpenFiltered
+		}
+	}
+
+	return results, nil
+}
+
+// icmpUnreachablePort extracts the destination port of the original UDP
+// datagram embedded in an ICMP Destination/Port Unreachable payload, so the
+// closed verdict can be attributed to the right in-flight probe.
+func icmpUnreachablePort(isIPv6 bool, payload []byte) (int, bool) {
+	networkLayerType := layers.LayerTypeIPv4
+	if isIPv6 {
+		networkLayerType = layers.LayerTypeIPv6
+	}
+	packet := gopacket.NewPacket(payload, networkLayerType, gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+	udpLayer := packet.Layer(layers.LayerTypeUDP)
+	if udpLayer == nil {
+		return 0, false
+	}
+	return int(udpLayer.(*layers.UDP).DstPort), true
+}
@@ -0,0 +1,63 @@
+package scan
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestIcmpUnreachablePort(t *testing.T) {
+	buildIPv4Payload := func(dstPort int) []byte {
+		ip4 := layers.IPv4{
+			SrcIP: net.ParseIP("192.0.2.1"), DstIP: net.ParseIP("192.0.2.2"),
+			Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP,
+		}
+		udp := layers.UDP{SrcPort: 12345, DstPort: layers.UDPPort(dstPort)}
+		udp.SetNetworkLayerForChecksum(&ip4)
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, &ip4, &udp, gopacket.Payload("x")); err != nil {
+			t.Fatalf("building IPv4 fixture: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	buildIPv6Payload := func(dstPort int) []byte {
+		ip6 := layers.IPv6{
+			SrcIP: net.ParseIP("2001:db8::1"), DstIP: net.ParseIP("2001:db8::2"),
+			Version: 6, HopLimit: 64, NextHeader: layers.IPProtocolUDP,
+		}
+		udp := layers.UDP{SrcPort: 12345, DstPort: layers.UDPPort(dstPort)}
+		udp.SetNetworkLayerForChecksum(&ip6)
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, &ip6, &udp, gopacket.Payload("x")); err != nil {
+			t.Fatalf("building IPv6 fixture: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name     string
+		isIPv6   bool
+		payload  []byte
+		wantPort int
+		wantOK   bool
+	}{
+		{name: "IPv4 embedded datagram yields the original dst port", isIPv6: false, payload: buildIPv4Payload(53), wantPort: 53, wantOK: true},
+		{name: "IPv6 embedded datagram yields the original dst port", isIPv6: true, payload: buildIPv6Payload(161), wantPort: 161, wantOK: true},
+		{name: "truncated payload with no UDP layer is rejected", isIPv6: false, payload: []byte{0x45, 0x00}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, ok := icmpUnreachablePort(tt.isIPv6, tt.payload)
+			if ok != tt.wantOK {
+				t.Fatalf("icmpUnreachablePort() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && port != tt.wantPort {
+				t.Fatalf("icmpUnreachablePort() port = %d, want %d", port, tt.wantPort)
+			}
+		})
+	}
+}